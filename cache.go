@@ -0,0 +1,173 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package flactomp3
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// CacheEntry records the last successful conversion of a single output
+// path, so an unchanged input can be skipped on a later run instead of
+// relying on file modification times.
+type CacheEntry struct {
+	// Digest is the hex-encoded BLAKE2b-256 digest of the source FLAC
+	// file's content.
+	Digest string `json:"digest"`
+
+	// Size is the size in bytes of the source FLAC file.
+	Size int64 `json:"size"`
+
+	// Settings is a hash of the encoder settings (bitrate, mode, and so
+	// on) used to produce the output; a change here invalidates the
+	// entry even when Digest is unchanged. See SettingsHasher.
+	Settings string `json:"settings"`
+}
+
+// SettingsHasher is implemented by Encoders whose settings should be
+// folded into the Cache key, so that changing the encoder's configuration
+// invalidates previously cached entries.
+type SettingsHasher interface {
+	SettingsHash() string
+}
+
+// Cache is a JSON-backed, content-hash keyed cache of prior conversions,
+// indexed by output path.
+type Cache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+	dirty   bool
+}
+
+// DefaultCachePath returns the default cache location,
+// $XDG_CACHE_HOME/flactomp3/index (typically ~/.cache/flactomp3/index).
+func DefaultCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "flactomp3", "index"), nil
+}
+
+// LoadCache reads the cache at path. A missing file is treated as an
+// empty cache.
+func LoadCache(path string) (*Cache, error) {
+	c := &Cache{path: path, entries: make(map[string]CacheEntry)}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&c.entries); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Lookup reports whether dst's cached entry matches digest and settings.
+func (c *Cache) Lookup(dst, digest, settings string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[dst]
+	return ok && e.Digest == digest && e.Settings == settings
+}
+
+// Entry returns dst's cached entry, if any.
+func (c *Cache) Entry(dst string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[dst]
+	return e, ok
+}
+
+// Store records that dst was produced from a source with the given
+// digest, size and settings.
+func (c *Cache) Store(dst, digest string, size int64, settings string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[dst] = CacheEntry{Digest: digest, Size: size, Settings: settings}
+	c.dirty = true
+}
+
+// Save writes the cache back to its path, if it has changed since it was
+// loaded.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o777); err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(f).Encode(c.entries); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// HashFile returns the hex-encoded BLAKE2b-256 digest and size of path's
+// content. The file is streamed rather than read fully into memory.
+func HashFile(path string) (digest string, size int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		return "", 0, err
+	}
+
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package flactomp3
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheLookupStore(t *testing.T) {
+	c, err := LoadCache(filepath.Join(t.TempDir(), "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Lookup("out.mp3", "digest", "settings") {
+		t.Error("Lookup on an empty cache reported a hit")
+	}
+
+	c.Store("out.mp3", "digest", 123, "settings")
+
+	if !c.Lookup("out.mp3", "digest", "settings") {
+		t.Error("Lookup after Store reported a miss")
+	}
+
+	if c.Lookup("out.mp3", "digest", "other-settings") {
+		t.Error("Lookup reported a hit with mismatched settings")
+	}
+
+	if c.Lookup("out.mp3", "other-digest", "settings") {
+		t.Error("Lookup reported a hit with mismatched digest")
+	}
+
+	e, ok := c.Entry("out.mp3")
+	if !ok {
+		t.Fatal("Entry reported no entry after Store")
+	}
+
+	if e.Digest != "digest" || e.Size != 123 || e.Settings != "settings" {
+		t.Errorf("Entry(\"out.mp3\") = %+v, want Digest:digest Size:123 Settings:settings", e)
+	}
+}
+
+func TestCacheSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+
+	c, err := LoadCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Store("out.mp3", "digest", 123, "settings")
+
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("cache file not written: %v", err)
+	}
+
+	c2, err := LoadCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !c2.Lookup("out.mp3", "digest", "settings") {
+		t.Error("reloaded cache lost the entry written before Save")
+	}
+}
+
+func TestLoadCacheMissing(t *testing.T) {
+	c, err := LoadCache(filepath.Join(t.TempDir(), "does-not-exist"))
+	if err != nil {
+		t.Fatalf("LoadCache on a missing file returned an error: %v", err)
+	}
+
+	if c.Lookup("out.mp3", "digest", "settings") {
+		t.Error("Lookup on a cache loaded from a missing file reported a hit")
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	digest1, size, err := HashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != 5 {
+		t.Errorf("HashFile size = %d, want 5", size)
+	}
+
+	digest2, _, err := HashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if digest1 != digest2 {
+		t.Errorf("HashFile is not deterministic: %q != %q", digest1, digest2)
+	}
+
+	if err := os.WriteFile(path, []byte("world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	digest3, _, err := HashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if digest1 == digest3 {
+		t.Error("HashFile returned the same digest for different content")
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/tmthrgd/flactomp3"
+	"github.com/tmthrgd/flactomp3/internal/fastwalk"
+)
+
+func worker(ctx context.Context, conv *flactomp3.Converter, rep *reporter, ch chan string, wg *sync.WaitGroup) {
+	for path := range ch {
+		rep.report(conv.ConvertResult(ctx, path))
+		wg.Done()
+	}
+}
+
+func main() {
+	recurse := flag.Bool("recurse", true, "whether to walk into child directories")
+	backend := flag.String("backend", "lame", `encoder backend to use, "lame" or "ffmpeg"`)
+	bitrate := flag.Int("bitrate", 192, "constant bitrate in kbps")
+	vbr := flag.Bool("vbr", false, "use variable bitrate encoding instead of constant bitrate")
+	vbrQuality := flag.Int("vbr-quality", 2, "VBR quality, 0 (highest) to 9 (lowest)")
+	ext := flag.String("ext", ".mp3", "extension to use for the output files")
+	dryRun := flag.Bool("dry-run", false, "print what would be converted without encoding")
+	cachePath := flag.String("cache", "", "path to the conversion cache (default $XDG_CACHE_HOME/flactomp3/index)")
+	force := flag.Bool("force", false, "bypass the conversion cache and re-encode everything")
+	verify := flag.Bool("verify", false, "re-hash the FLAC inputs and report outputs whose cache entry no longer matches, instead of converting")
+	report := flag.String("report", "", "path to write a JSON-lines report of each conversion to")
+	resume := flag.String("resume", "", "path to a resume file: read incomplete paths from it at startup if present, and (re-)write it if interrupted")
+	flag.Parse()
+
+	var enc flactomp3.Encoder
+	switch *backend {
+	case "lame":
+		enc = &flactomp3.LameEncoder{Bitrate: *bitrate, VBR: *vbr, VBRQuality: *vbrQuality}
+	case "ffmpeg":
+		enc = &flactomp3.FFmpegEncoder{Bitrate: *bitrate, VBR: *vbr, VBRQuality: *vbrQuality}
+	default:
+		fmt.Fprintf(os.Stderr, "flactomp3: unknown backend %q\n", *backend)
+		os.Exit(2)
+	}
+
+	conv := flactomp3.NewConverter(enc)
+	conv.Ext = *ext
+	conv.DryRun = *dryRun
+	conv.Force = *force
+
+	resolvedCachePath := *cachePath
+	if resolvedCachePath == "" {
+		var err error
+		if resolvedCachePath, err = flactomp3.DefaultCachePath(); err != nil {
+			fmt.Fprintf(os.Stderr, "flactomp3: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	cache, err := flactomp3.LoadCache(resolvedCachePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flactomp3: %v\n", err)
+		os.Exit(1)
+	}
+	conv.Cache = cache
+
+	dir := flag.Arg(0)
+	if dir == "" {
+		dir = "."
+	}
+
+	if *verify {
+		verifyOutputs(conv, dir, *recurse)
+		return
+	}
+
+	resumePaths, err := readResumeFile(*resume)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flactomp3: reading resume file: %v\n", err)
+		os.Exit(1)
+	}
+
+	rep, err := newReporter(*report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flactomp3: %v\n", err)
+		os.Exit(1)
+	}
+
+	var wg sync.WaitGroup
+
+	work := make(chan string, 32)
+	defer close(work)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < cap(work); i++ {
+		go worker(ctx, conv, rep, work, &wg)
+	}
+
+	enqueue := func(path string) {
+		wg.Add(1)
+		rep.enqueue(path)
+		work <- path
+	}
+
+	if resumePaths != nil {
+		for _, path := range resumePaths {
+			enqueue(path)
+		}
+	} else if err := fastwalk.Walk(dir, func(path string, typ os.FileMode) error {
+		if typ&os.ModeDir != 0 {
+			if !*recurse && path != dir {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if filepath.Ext(path) != ".flac" {
+			return nil
+		}
+
+		enqueue(path)
+		return nil
+	}); err != nil {
+		panic(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wg.Wait()
+	}()
+
+	// termination handler
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
+
+	interrupted := false
+
+	select {
+	case <-done:
+	case <-term:
+		signal.Stop(term)
+		interrupted = true
+
+		if err := writeResumeFile(*resume, rep.incomplete()); err != nil {
+			fmt.Fprintf(os.Stderr, "flactomp3: writing resume file: %v\n", err)
+		}
+
+		cancel()
+		<-done
+	}
+
+	if !interrupted {
+		if err := clearResumeFile(*resume); err != nil {
+			fmt.Fprintf(os.Stderr, "flactomp3: clearing resume file: %v\n", err)
+		}
+	}
+
+	if err := rep.close(); err != nil {
+		fmt.Fprintf(os.Stderr, "flactomp3: writing report: %v\n", err)
+	}
+
+	if err := cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "flactomp3: saving cache: %v\n", err)
+		os.Exit(1)
+	}
+
+	if interrupted || rep.failed() {
+		os.Exit(1)
+	}
+}
+
+// verifyOutputs walks dir re-hashing every FLAC file and checking the
+// digest against conv's Cache entry for that file's output, reporting any
+// output whose entry no longer matches without re-encoding anything.
+func verifyOutputs(conv *flactomp3.Converter, dir string, recurse bool) {
+	var mismatches int64
+
+	if err := fastwalk.Walk(dir, func(path string, typ os.FileMode) error {
+		if typ&os.ModeDir != 0 {
+			if !recurse && path != dir {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if filepath.Ext(path) != ".flac" {
+			return nil
+		}
+
+		ok, err := conv.VerifyOutput(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "<%s>: %v\n", path, err)
+			return nil
+		}
+
+		if !ok {
+			fmt.Printf("stale: %s\n", conv.OutPath(path))
+			atomic.AddInt64(&mismatches, 1)
+		}
+
+		return nil
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "flactomp3: %v\n", err)
+		os.Exit(1)
+	}
+
+	if mismatches > 0 {
+		os.Exit(1)
+	}
+}
+
+// readResumeFile reads the JSON array of paths left by a previous
+// interrupted run. It returns a nil slice, not an error, if path is empty
+// or the file does not exist.
+func readResumeFile(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// writeResumeFile records paths, the work left incomplete by an
+// interrupted run, as a JSON array at path. It is a no-op if path is
+// empty.
+func writeResumeFile(path string, paths []string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(paths)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o666)
+}
+
+// clearResumeFile removes the resume file at path left by a previous
+// interrupted run, now that its work has finished without being
+// interrupted again. It is a no-op if path is empty or the file does not
+// exist.
+func clearResumeFile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// reporter aggregates per-file Results: it prints failures to stderr,
+// optionally mirrors every Result as a line of a JSON-lines report, and
+// tracks which enqueued paths have not yet been reported on so an
+// interrupted run can save a resume file.
+type reporter struct {
+	mu        sync.Mutex
+	enc       *json.Encoder
+	f         *os.File
+	anyFailed bool
+
+	pendingMu sync.Mutex
+	pending   map[string]struct{}
+}
+
+func newReporter(reportPath string) (*reporter, error) {
+	r := &reporter{pending: make(map[string]struct{})}
+
+	if reportPath == "" {
+		return r, nil
+	}
+
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r.f = f
+	r.enc = json.NewEncoder(f)
+	return r, nil
+}
+
+// enqueue records path as having been handed off to a worker.
+func (r *reporter) enqueue(path string) {
+	r.pendingMu.Lock()
+	r.pending[path] = struct{}{}
+	r.pendingMu.Unlock()
+}
+
+// report records res, the outcome of converting res.Path.
+func (r *reporter) report(res flactomp3.Result) {
+	r.pendingMu.Lock()
+	delete(r.pending, res.Path)
+	r.pendingMu.Unlock()
+
+	if res.Status == "dry-run" {
+		fmt.Printf("would convert: %s -> %s\n", res.Path, res.Output)
+	}
+
+	if res.Status == "error" {
+		fmt.Fprintf(os.Stderr, "<%s>: %s\n", res.Path, res.Error)
+
+		r.mu.Lock()
+		r.anyFailed = true
+		r.mu.Unlock()
+	}
+
+	if r.enc == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.enc.Encode(res); err != nil {
+		fmt.Fprintf(os.Stderr, "flactomp3: writing report: %v\n", err)
+	}
+}
+
+// incomplete returns the paths that were enqueued but have not yet been
+// reported on.
+func (r *reporter) incomplete() []string {
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+
+	paths := make([]string, 0, len(r.pending))
+	for path := range r.pending {
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+// failed reports whether any conversion reported so far has failed.
+func (r *reporter) failed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.anyFailed
+}
+
+func (r *reporter) close() error {
+	if r.f == nil {
+		return nil
+	}
+
+	return r.f.Close()
+}
@@ -0,0 +1,189 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+// Package flactomp3 converts FLAC files to another audio format using a
+// pluggable Encoder backend.
+package flactomp3
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var pathSanitizer = strings.NewReplacer(":", "-")
+
+// Converter drives the conversion of FLAC files using an Encoder.
+type Converter struct {
+	// Encoder performs the tag probing and encoding.
+	Encoder Encoder
+
+	// Ext is the extension, including the leading dot, appended to the
+	// output path. Defaults to ".mp3" if empty.
+	Ext string
+
+	// DryRun reports what would be converted without calling
+	// Encoder.Encode; Encoder.ProbeTags is still called.
+	DryRun bool
+
+	// Cache, if non-nil, is consulted before encoding: if path's content
+	// digest and Encoder's settings match OutPath(path)'s cached entry and
+	// OutPath(path) exists, the conversion is skipped.
+	Cache *Cache
+
+	// Force bypasses Cache and always re-encodes.
+	Force bool
+}
+
+// NewConverter returns a Converter that encodes using enc.
+func NewConverter(enc Encoder) *Converter {
+	return &Converter{Encoder: enc}
+}
+
+// OutPath returns the destination path Convert would write path's encoded
+// output to.
+func (c *Converter) OutPath(path string) string {
+	ext := c.Ext
+	if ext == "" {
+		ext = ".mp3"
+	}
+
+	dir, file := filepath.Split(path)
+	file = "." + pathSanitizer.Replace(file) + ext
+	return dir + file
+}
+
+// convert does the probe/cache-check/encode work for path, returning a
+// short status ("ok", "skipped" for a cache hit, or "dry-run") alongside
+// any error.
+func (c *Converter) convert(ctx context.Context, path string) (status string, err error) {
+	dst := c.OutPath(path)
+
+	var digest string
+	var size int64
+	var settings string
+
+	if c.Cache != nil {
+		if digest, size, err = HashFile(path); err != nil {
+			return "", err
+		}
+
+		if h, ok := c.Encoder.(SettingsHasher); ok {
+			settings = h.SettingsHash()
+		}
+
+		if !c.Force && c.Cache.Lookup(dst, digest, settings) {
+			if _, statErr := os.Stat(dst); statErr == nil {
+				return "skipped", nil
+			}
+		}
+	}
+
+	meta, err := c.Encoder.ProbeTags(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	if c.DryRun {
+		return "dry-run", nil
+	}
+
+	tags := TagsFromMeta(meta)
+
+	cover, cleanup, err := coverArt(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+	tags.CoverArt = cover
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	if err := c.Encoder.Encode(ctx, src, dst, tags); err != nil {
+		return "", err
+	}
+
+	if c.Cache != nil {
+		c.Cache.Store(dst, digest, size, settings)
+	}
+
+	return "ok", nil
+}
+
+// Convert probes path's tags and encodes it to OutPath(path), unless Cache
+// reports that an up to date output already exists.
+func (c *Converter) Convert(ctx context.Context, path string) error {
+	_, err := c.convert(ctx, path)
+	return err
+}
+
+// Result records the outcome of converting a single file, suitable for
+// aggregation into an exit code or a JSON-lines report by the caller.
+type Result struct {
+	Path        string `json:"path"`
+	Output      string `json:"output"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	DurationMS  int64  `json:"duration_ms"`
+	InputBytes  int64  `json:"input_bytes"`
+	OutputBytes int64  `json:"output_bytes"`
+}
+
+// ConvertResult is like Convert, but returns a Result describing what
+// happened instead of a bare error.
+func (c *Converter) ConvertResult(ctx context.Context, path string) Result {
+	start := time.Now()
+	dst := c.OutPath(path)
+
+	res := Result{Path: path, Output: dst}
+
+	status, err := c.convert(ctx, path)
+	res.DurationMS = time.Since(start).Milliseconds()
+
+	if err != nil {
+		res.Status = "error"
+		res.Error = err.Error()
+	} else {
+		res.Status = status
+	}
+
+	if fi, statErr := os.Stat(path); statErr == nil {
+		res.InputBytes = fi.Size()
+	}
+
+	if fi, statErr := os.Stat(dst); statErr == nil {
+		res.OutputBytes = fi.Size()
+	}
+
+	return res
+}
+
+// VerifyOutput re-hashes path and reports whether Cache's entry for
+// OutPath(path) still matches its content; it does not re-encode.
+func (c *Converter) VerifyOutput(path string) (ok bool, err error) {
+	if c.Cache == nil {
+		return false, ErrNoCache
+	}
+
+	dst := c.OutPath(path)
+
+	digest, _, err := HashFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	var settings string
+	if h, ok := c.Encoder.(SettingsHasher); ok {
+		settings = h.SettingsHash()
+	}
+
+	return c.Cache.Lookup(dst, digest, settings), nil
+}
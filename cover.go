@@ -0,0 +1,72 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package flactomp3
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// coverArtNames are sibling files checked for cover art when path has no
+// embedded FLAC PICTURE block, in order of preference.
+var coverArtNames = []string{"cover.jpg", "cover.png", "folder.jpg", "folder.png"}
+
+// coverArt resolves the cover art for the FLAC file at path, preferring an
+// embedded PICTURE block over a sibling cover.jpg/folder.jpg. The returned
+// cleanup must be called once the caller is done with the path; it
+// removes the temporary file extracted for an embedded picture, or is a
+// no-op for a sibling file. Both the path and cleanup are "" and a no-op
+// respectively when no cover art was found.
+func coverArt(ctx context.Context, path string) (coverPath string, cleanup func(), err error) {
+	tmp, err := extractEmbeddedPicture(ctx, path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if tmp != "" {
+		return tmp, func() { os.Remove(tmp) }, nil
+	}
+
+	dir := filepath.Dir(path)
+	for _, name := range coverArtNames {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p, func() {}, nil
+		}
+	}
+
+	return "", func() {}, nil
+}
+
+// extractEmbeddedPicture exports path's embedded FLAC PICTURE block, if
+// any, to a temporary file and returns its name. It returns "" without an
+// error if the FLAC file has no PICTURE block.
+func extractEmbeddedPicture(ctx context.Context, path string) (string, error) {
+	f, err := os.CreateTemp("", "flactomp3-cover-*.jpg")
+	if err != nil {
+		return "", err
+	}
+
+	tmp := f.Name()
+	f.Close()
+
+	cmd := exec.CommandContext(ctx, "metaflac", "--export-picture-to="+tmp, path)
+	if err := cmd.Run(); err != nil {
+		// metaflac exits non-zero when the file has no PICTURE block;
+		// treat that as "no cover art" rather than a hard failure.
+		os.Remove(tmp)
+		return "", nil
+	}
+
+	if fi, err := os.Stat(tmp); err != nil || fi.Size() == 0 {
+		os.Remove(tmp)
+		return "", nil
+	}
+
+	return tmp, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package flactomp3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// Encoder encodes a FLAC file into some other audio format, embedding tags
+// along the way.
+//
+// Implementations are free to shell out to whatever tools they need to
+// perform the decode and encode; these do not have to be two separate
+// subprocesses, see FFmpegEncoder.
+type Encoder interface {
+	// ProbeTags reads the Vorbis comments of the FLAC file at path. A key
+	// may map to more than one value, since Vorbis comments (e.g.
+	// ARTIST) are allowed to repeat.
+	ProbeTags(ctx context.Context, path string) (map[string][]string, error)
+
+	// Encode reads the FLAC file content from src and writes the encoded
+	// result to dst, embedding tags.
+	Encode(ctx context.Context, src io.Reader, dst string, tags Tags) error
+}
+
+var variableSeperator = []byte{'='}
+
+// probeTagsWithMetaflac runs metaflac to extract the Vorbis comments of the
+// FLAC file at path. It is shared by LameEncoder and FFmpegEncoder, both of
+// which rely on metaflac for tag extraction.
+func probeTagsWithMetaflac(ctx context.Context, path string) (map[string][]string, error) {
+	cmd := exec.CommandContext(ctx, "metaflac", "--export-tags-to=-", path)
+
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: metaflac: %w", ErrTagProbe, err)
+	}
+
+	s := bufio.NewScanner(&buf)
+	meta := make(map[string][]string)
+
+	for s.Scan() {
+		tok := bytes.SplitN(s.Bytes(), variableSeperator, 2)
+		if len(tok) < 2 {
+			return nil, fmt.Errorf("%w: invalid variable format", ErrTagProbe)
+		}
+
+		key := string(tok[0])
+		meta[key] = append(meta[key], string(tok[1]))
+	}
+
+	if s.Err() != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTagProbe, s.Err())
+	}
+
+	return meta, nil
+}
@@ -0,0 +1,30 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package flactomp3
+
+import "errors"
+
+// Sentinel errors identifying the stage of the conversion pipeline that
+// failed. Encoder implementations wrap the underlying subprocess error
+// with one of these so callers can tell tag-parse, decode and encode
+// failures apart with errors.Is, rather than pattern-matching on a
+// message string.
+var (
+	// ErrTagProbe is returned, wrapped, when reading a FLAC file's tags
+	// fails.
+	ErrTagProbe = errors.New("flactomp3: tag probe failed")
+
+	// ErrFlacDecode is returned, wrapped, when decoding the source FLAC
+	// stream fails.
+	ErrFlacDecode = errors.New("flactomp3: flac decode failed")
+
+	// ErrEncode is returned, wrapped, when encoding the output fails.
+	ErrEncode = errors.New("flactomp3: encode failed")
+
+	// ErrNoCache is returned by Converter.VerifyOutput when Cache is nil,
+	// since there is nothing to verify output against.
+	ErrNoCache = errors.New("flactomp3: no cache configured")
+)
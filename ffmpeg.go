@@ -0,0 +1,108 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package flactomp3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// FFmpegEncoder encodes using a single ffmpeg process with the libmp3lame
+// codec, decoding src and encoding to dst in one step. Unlike LameEncoder
+// it shells out to only one subprocess, and ffmpeg's broader demuxer
+// support means src need not be a FLAC stream.
+type FFmpegEncoder struct {
+	// Bitrate is the constant bitrate in kbps used unless VBR is set.
+	// Defaults to 192 if zero.
+	Bitrate int
+
+	// VBRQuality is the ffmpeg libmp3lame -q:a quality setting (0 is
+	// highest quality, 9 is lowest), used when VBR is set.
+	VBRQuality int
+
+	// VBR selects variable bitrate encoding instead of the default
+	// constant bitrate.
+	VBR bool
+}
+
+func (e *FFmpegEncoder) ProbeTags(ctx context.Context, path string) (map[string][]string, error) {
+	return probeTagsWithMetaflac(ctx, path)
+}
+
+// SettingsHash implements SettingsHasher, so a change in bitrate or mode
+// invalidates cache entries produced with the previous settings.
+func (e *FFmpegEncoder) SettingsHash() string {
+	if e.VBR {
+		return fmt.Sprintf("ffmpeg:vbr:%d", e.VBRQuality)
+	}
+
+	b := e.Bitrate
+	if b == 0 {
+		b = 192
+	}
+
+	return fmt.Sprintf("ffmpeg:cbr:%d", b)
+}
+
+func (e *FFmpegEncoder) Encode(ctx context.Context, src io.Reader, dst string, tags Tags) error {
+	args := []string{"-y", "-i", "pipe:0"}
+
+	if tags.CoverArt != "" {
+		args = append(args, "-i", tags.CoverArt,
+			"-map", "0:a", "-map", "1:v",
+			"-c:v", "copy", "-disposition:v", "attached_pic")
+	}
+
+	args = append(args, "-codec:a", "libmp3lame")
+
+	if e.VBR {
+		args = append(args, "-q:a", strconv.Itoa(e.VBRQuality))
+	} else {
+		b := e.Bitrate
+		if b == 0 {
+			b = 192
+		}
+
+		args = append(args, "-b:a", strconv.Itoa(b)+"k")
+	}
+
+	disc := tags.DiscNumber
+	if disc != "" && tags.TotalDiscs != "" {
+		disc += "/" + tags.TotalDiscs
+	}
+
+	args = append(args,
+		"-metadata", "title="+tags.Title,
+		"-metadata", "track="+tags.TrackNumber,
+		"-metadata", "genre="+tags.Genre,
+		"-metadata", "artist="+tags.ArtistTag(),
+		"-metadata", "album="+tags.Album,
+		"-metadata", "date="+tags.Date,
+		"-metadata", "album_artist="+tags.AlbumArtist,
+		"-metadata", "disc="+disc,
+		"-metadata", "composer="+tags.Composer,
+		"-metadata", "comment="+tags.Comment,
+		"-metadata", "replaygain_track_gain="+tags.ReplayGain.TrackGain,
+		"-metadata", "replaygain_track_peak="+tags.ReplayGain.TrackPeak,
+		"-metadata", "replaygain_album_gain="+tags.ReplayGain.AlbumGain,
+		"-metadata", "replaygain_album_peak="+tags.ReplayGain.AlbumPeak,
+		dst)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdin = src
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("%w: ffmpeg: %w", ErrEncode, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package flactomp3
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// writeExtendedID3Frames shells out to id3v2 to add the frames lame's own
+// tagging flags don't cover: album artist, disc/track totals, composer,
+// comment and ReplayGain. It is a no-op if tags carries none of them.
+func writeExtendedID3Frames(ctx context.Context, path string, tags Tags) error {
+	var args []string
+
+	if tags.AlbumArtist != "" {
+		args = append(args, "--TPE2", tags.AlbumArtist)
+	}
+
+	if tags.DiscNumber != "" {
+		disc := tags.DiscNumber
+		if tags.TotalDiscs != "" {
+			disc += "/" + tags.TotalDiscs
+		}
+
+		args = append(args, "--TPOS", disc)
+	}
+
+	if tags.TrackTotal != "" {
+		args = append(args, "--TXXX", "TRACKTOTAL:"+tags.TrackTotal)
+	}
+
+	if tags.Composer != "" {
+		args = append(args, "--TCOM", tags.Composer)
+	}
+
+	if tags.Comment != "" {
+		args = append(args, "--COMM", "und::"+tags.Comment)
+	}
+
+	if g := tags.ReplayGain.TrackGain; g != "" {
+		args = append(args, "--TXXX", "REPLAYGAIN_TRACK_GAIN:"+g)
+	}
+
+	if g := tags.ReplayGain.TrackPeak; g != "" {
+		args = append(args, "--TXXX", "REPLAYGAIN_TRACK_PEAK:"+g)
+	}
+
+	if g := tags.ReplayGain.AlbumGain; g != "" {
+		args = append(args, "--TXXX", "REPLAYGAIN_ALBUM_GAIN:"+g)
+	}
+
+	if g := tags.ReplayGain.AlbumPeak; g != "" {
+		args = append(args, "--TXXX", "REPLAYGAIN_ALBUM_PEAK:"+g)
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	args = append(args, path)
+
+	cmd := exec.CommandContext(ctx, "id3v2", args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+// Package fastwalk provides a directory walk that avoids the per-entry
+// Lstat filepath.Walk performs, adapting the technique used by
+// golang.org/x/tools/internal/fastwalk: on Unix the file type is read
+// directly from the kernel's directory-entry buffer, falling back to
+// Lstat only when that type is unknown.
+package fastwalk
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Func is called for each file or directory visited by Walk, including
+// root itself. typ is the entry's type as reported by readDirNames; where
+// the kernel could not report a type, Walk falls back to Lstat and typ is
+// the type Lstat reports. Returning filepath.SkipDir from fn skips the rest
+// of that directory.
+type Func func(path string, typ os.FileMode) error
+
+// Walk walks the file tree rooted at root, calling fn for each file or
+// directory. Directories are scanned concurrently, fanned out to a worker
+// pool bounded by GOMAXPROCS, so enumeration of one directory overlaps
+// with enumeration of its siblings and with any work fn itself kicks off.
+func Walk(root string, fn Func) error {
+	info, err := os.Lstat(root)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(root, info.Mode()&os.ModeType); err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeDir == 0 {
+		return nil
+	}
+
+	eg, _ := errgroup.WithContext(context.Background())
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+
+	var walkDir func(dir string) error
+	walkDir = func(dir string) error {
+		return readDirNames(dir, func(name string, typ os.FileMode) error {
+			path := filepath.Join(dir, name)
+
+			if typ == os.ModeIrregular {
+				fi, err := os.Lstat(path)
+				if err != nil {
+					return err
+				}
+
+				typ = fi.Mode() & os.ModeType
+			}
+
+			if err := fn(path, typ); err != nil {
+				if err == filepath.SkipDir {
+					return nil
+				}
+
+				return err
+			}
+
+			if typ&os.ModeDir == 0 {
+				return nil
+			}
+
+			select {
+			case sem <- struct{}{}:
+				eg.Go(func() error {
+					defer func() { <-sem }()
+					return walkDir(path)
+				})
+				return nil
+			default:
+				// Worker pool is saturated; keep walking inline
+				// rather than blocking the caller on sem.
+				return walkDir(path)
+			}
+		})
+	}
+
+	eg.Go(func() error { return walkDir(root) })
+	return eg.Wait()
+}
+
+// WalkDir is a portable fallback for callers that would rather use the
+// standard library's own Lstat-avoiding walk (filepath.WalkDir, backed by
+// fs.DirEntry) than this package's concurrent, getdents-based Walk.
+func WalkDir(root string, fn Func) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		return fn(path, d.Type())
+	})
+}
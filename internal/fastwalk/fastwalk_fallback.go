@@ -0,0 +1,30 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+//go:build !(linux || darwin || freebsd || dragonfly)
+
+package fastwalk
+
+import "os"
+
+// readDirNames is the portable fallback used on platforms without a
+// getdents-based implementation (Windows, Plan 9, ...). It relies on
+// fs.DirEntry.Type(), which the standard library already populates from
+// the OS's own directory-entry type where one is available, without an
+// extra Lstat.
+func readDirNames(dirName string, fn func(name string, typ os.FileMode) error) error {
+	entries, err := os.ReadDir(dirName)
+	if err != nil {
+		return err
+	}
+
+	for _, ent := range entries {
+		if err := fn(ent.Name(), ent.Type()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+//go:build linux || darwin || freebsd || dragonfly
+
+package fastwalk
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// readDirNames reads the names and kernel-reported types of dirName's
+// entries directly out of the raw getdents buffer, without Lstat-ing any
+// of them. Entries whose type the kernel didn't report (DT_UNKNOWN) are
+// passed through as os.ModeIrregular for the caller to Lstat itself.
+func readDirNames(dirName string, fn func(name string, typ os.FileMode) error) error {
+	fd, err := syscall.Open(dirName, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: dirName, Err: err}
+	}
+	defer syscall.Close(fd)
+
+	buf := make([]byte, 8192)
+	for {
+		n, err := syscall.ReadDirent(fd, buf)
+		if err != nil {
+			return &os.PathError{Op: "readdirent", Path: dirName, Err: err}
+		}
+		if n <= 0 {
+			return nil
+		}
+
+		for off := 0; off < n; {
+			de := (*syscall.Dirent)(unsafe.Pointer(&buf[off]))
+
+			reclen := int(de.Reclen)
+			if reclen <= 0 {
+				break
+			}
+
+			name := direntName(buf[off : off+reclen])
+			typ := direntType(de.Type)
+			off += reclen
+
+			if name == "" || name == "." || name == ".." {
+				continue
+			}
+
+			if err := fn(name, typ); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// namOffset is the byte offset of the Name field within syscall.Dirent,
+// i.e. the fixed-size header (Ino, Off, Reclen, Type, ...) that precedes
+// it.
+var namOffset = int(unsafe.Offsetof(syscall.Dirent{}.Name))
+
+// direntName extracts the NUL-terminated file name from rec, the raw
+// getdents record bytes for a single entry (buf[off:off+reclen]). It
+// scans only within rec, never past it, since rec's length reflects the
+// record's actual Reclen rather than the fixed 256-byte Name array,
+// which trailing entries in a ReadDirent fill may not have room for.
+func direntName(rec []byte) string {
+	b := rec[namOffset:]
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+
+	return string(b)
+}
+
+func direntType(t uint8) os.FileMode {
+	switch t {
+	case syscall.DT_DIR:
+		return os.ModeDir
+	case syscall.DT_REG:
+		return 0
+	case syscall.DT_LNK:
+		return os.ModeSymlink
+	case syscall.DT_FIFO:
+		return os.ModeNamedPipe
+	case syscall.DT_SOCK:
+		return os.ModeSocket
+	case syscall.DT_CHR:
+		return os.ModeDevice | os.ModeCharDevice
+	case syscall.DT_BLK:
+		return os.ModeDevice
+	default:
+		// DT_UNKNOWN, or anything unexpected: let the caller Lstat.
+		return os.ModeIrregular
+	}
+}
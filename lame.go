@@ -0,0 +1,126 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package flactomp3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// LameEncoder encodes using the external flac and lame binaries: flac
+// decodes src to WAV on stdout, which is piped directly into lame's stdin.
+type LameEncoder struct {
+	// Bitrate is the constant bitrate in kbps used unless VBR is set.
+	// Defaults to 192 if zero.
+	Bitrate int
+
+	// VBRQuality is the lame -V quality setting (0 is highest quality, 9
+	// is lowest), used when VBR is set.
+	VBRQuality int
+
+	// VBR selects variable bitrate encoding instead of the default
+	// constant bitrate.
+	VBR bool
+}
+
+func (e *LameEncoder) ProbeTags(ctx context.Context, path string) (map[string][]string, error) {
+	return probeTagsWithMetaflac(ctx, path)
+}
+
+// SettingsHash implements SettingsHasher, so a change in bitrate or mode
+// invalidates cache entries produced with the previous settings.
+func (e *LameEncoder) SettingsHash() string {
+	if e.VBR {
+		return fmt.Sprintf("lame:vbr:%d", e.VBRQuality)
+	}
+
+	b := e.Bitrate
+	if b == 0 {
+		b = 192
+	}
+
+	return fmt.Sprintf("lame:cbr:%d", b)
+}
+
+func (e *LameEncoder) bitrateArgs() []string {
+	if e.VBR {
+		return []string{"-V", strconv.Itoa(e.VBRQuality)}
+	}
+
+	b := e.Bitrate
+	if b == 0 {
+		b = 192
+	}
+
+	return []string{"-b", strconv.Itoa(b)}
+}
+
+func (e *LameEncoder) Encode(ctx context.Context, src io.Reader, dst string, tags Tags) error {
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	cmd1 := exec.CommandContext(egCtx, "flac", "-c", "-d", "-")
+	cmd1.Stdin = src
+	cmd1.Stderr = os.Stderr
+
+	args := append(e.bitrateArgs(),
+		"-h",
+		"--tt", tags.Title,
+		"--tn", tags.TrackNumber,
+		"--tg", tags.Genre,
+		"--ta", tags.ArtistTag(),
+		"--tl", tags.Album,
+		"--ty", tags.Date,
+		"--add-id3v2",
+	)
+
+	if tags.CoverArt != "" {
+		args = append(args, "--ti", tags.CoverArt)
+	}
+
+	args = append(args, "-", dst)
+
+	cmd2 := exec.CommandContext(egCtx, "lame", args...)
+	cmd2.Stdout, cmd2.Stderr = os.Stdout, os.Stderr
+
+	var err error
+	if cmd2.Stdin, err = cmd1.StdoutPipe(); err != nil {
+		return err
+	}
+
+	eg.Go(func() error {
+		if err := cmd1.Run(); err != nil {
+			return fmt.Errorf("%w: flac: %w", ErrFlacDecode, err)
+		}
+
+		return nil
+	})
+
+	eg.Go(func() error {
+		if err := cmd2.Run(); err != nil {
+			return fmt.Errorf("%w: lame: %w", ErrEncode, err)
+		}
+
+		return nil
+	})
+
+	if err := eg.Wait(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	if err := writeExtendedID3Frames(ctx, dst, tags); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("%w: id3v2: %w", ErrEncode, err)
+	}
+
+	return nil
+}
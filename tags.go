@@ -0,0 +1,96 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package flactomp3
+
+import "strings"
+
+// ReplayGain holds the REPLAYGAIN_* Vorbis comments of a FLAC file, if
+// present.
+type ReplayGain struct {
+	TrackGain string
+	TrackPeak string
+	AlbumGain string
+	AlbumPeak string
+}
+
+// Tags holds the FLAC file metadata carried over into the encoded output,
+// plus any embedded or sibling cover art found alongside it.
+type Tags struct {
+	Title       string
+	TrackNumber string
+	Genre       string
+	Artist      string
+	Album       string
+	Date        string
+
+	// Artists holds every ARTIST comment in declaration order; Artist is
+	// Artists[0] where present. Vorbis comments allow a field to repeat,
+	// which a plain map[string]string would silently collapse to the
+	// last value.
+	Artists []string
+
+	AlbumArtist string
+	DiscNumber  string
+	TotalDiscs  string
+	TrackTotal  string
+	Composer    string
+	Comment     string
+
+	ReplayGain ReplayGain
+
+	// CoverArt is the path to an image file holding the track's cover
+	// art, either a temporary file extracted from an embedded FLAC
+	// PICTURE block or a sibling cover.jpg/folder.jpg. Empty if no cover
+	// art was found.
+	CoverArt string
+}
+
+// ArtistTag joins every value in Artists with "/", the conventional
+// separator for a multi-valued artist credit in ID3v2.3 and other tag
+// formats that have no repeatable artist frame of their own. It returns
+// Artist unchanged when there is at most one artist.
+func (t Tags) ArtistTag() string {
+	if len(t.Artists) > 1 {
+		return strings.Join(t.Artists, "/")
+	}
+
+	return t.Artist
+}
+
+// first returns the first value of key in meta, or "" if key is absent.
+func first(meta map[string][]string, key string) string {
+	if v := meta[key]; len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
+
+// TagsFromMeta builds a Tags from the raw, possibly multi-valued,
+// key/value pairs returned by an Encoder's ProbeTags method.
+func TagsFromMeta(meta map[string][]string) Tags {
+	return Tags{
+		Title:       first(meta, "TITLE"),
+		TrackNumber: first(meta, "TRACKNUMBER"),
+		Genre:       first(meta, "GENRE"),
+		Artist:      first(meta, "ARTIST"),
+		Artists:     meta["ARTIST"],
+		Album:       first(meta, "ALBUM"),
+		Date:        first(meta, "DATE"),
+		AlbumArtist: first(meta, "ALBUMARTIST"),
+		DiscNumber:  first(meta, "DISCNUMBER"),
+		TotalDiscs:  first(meta, "TOTALDISCS"),
+		TrackTotal:  first(meta, "TRACKTOTAL"),
+		Composer:    first(meta, "COMPOSER"),
+		Comment:     first(meta, "COMMENT"),
+		ReplayGain: ReplayGain{
+			TrackGain: first(meta, "REPLAYGAIN_TRACK_GAIN"),
+			TrackPeak: first(meta, "REPLAYGAIN_TRACK_PEAK"),
+			AlbumGain: first(meta, "REPLAYGAIN_ALBUM_GAIN"),
+			AlbumPeak: first(meta, "REPLAYGAIN_ALBUM_PEAK"),
+		},
+	}
+}
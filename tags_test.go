@@ -0,0 +1,78 @@
+// Copyright 2017 Tom Thorogood. All rights reserved.
+// Use of this source code is governed by a
+// Modified BSD License license that can be found in
+// the LICENSE file.
+
+package flactomp3
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagsFromMeta(t *testing.T) {
+	meta := map[string][]string{
+		"TITLE":       {"Song"},
+		"ARTIST":      {"Alice", "Bob"},
+		"ALBUM":       {"Record"},
+		"TRACKNUMBER": {"1"},
+	}
+
+	got := TagsFromMeta(meta)
+
+	want := Tags{
+		Title:       "Song",
+		TrackNumber: "1",
+		Artist:      "Alice",
+		Artists:     []string{"Alice", "Bob"},
+		Album:       "Record",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TagsFromMeta(%v) = %+v, want %+v", meta, got, want)
+	}
+}
+
+func TestTagsFromMetaEmpty(t *testing.T) {
+	got := TagsFromMeta(nil)
+
+	if got.Title != "" || got.Artist != "" || got.Artists != nil {
+		t.Errorf("TagsFromMeta(nil) = %+v, want zero value", got)
+	}
+}
+
+func TestTagsArtistTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tags Tags
+		want string
+	}{
+		{"no artists", Tags{}, ""},
+		{"single artist", Tags{Artist: "Alice", Artists: []string{"Alice"}}, "Alice"},
+		{
+			"multiple artists",
+			Tags{Artist: "Alice", Artists: []string{"Alice", "Bob", "Carol"}},
+			"Alice/Bob/Carol",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tags.ArtistTag(); got != tt.want {
+				t.Errorf("ArtistTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirst(t *testing.T) {
+	meta := map[string][]string{"ARTIST": {"Alice", "Bob"}}
+
+	if got := first(meta, "ARTIST"); got != "Alice" {
+		t.Errorf("first(meta, %q) = %q, want %q", "ARTIST", got, "Alice")
+	}
+
+	if got := first(meta, "MISSING"); got != "" {
+		t.Errorf("first(meta, %q) = %q, want empty", "MISSING", got)
+	}
+}